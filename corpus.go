@@ -0,0 +1,414 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// corpusVersion is written as the header line of every corpus file this
+// package writes, matching the header Go's native `go test -fuzz` corpus
+// files carry, so the two are interchangeable.
+const corpusVersion = "go test fuzz v1"
+
+var errCorpusFormat = fmt.Errorf("fuzz: malformed corpus file")
+
+// SeededRand returns a *rand.Rand whose entire output is determined by
+// seed, so that regenerating a Value with the same seed and the same
+// bindings always reproduces the same struct.
+func SeededRand(seed []byte) *rand.Rand {
+	h := fnv.New64a()
+	h.Write(seed)
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
+
+// Record generates one value from f using r and n and writes it to w in the
+// same line-oriented, typed-literal format native Go fuzz corpus files use,
+// so the result can be copied into testdata/fuzz/<Test> and picked up
+// directly by `go test -fuzz`.
+func (f *Fuzz) Record(w io.Writer, r *rand.Rand, n int) error {
+	v, err := f.Value(r, n)
+	if err != nil {
+		return err
+	}
+	return writeCorpusEntry(w, v)
+}
+
+// Seed loads a corpus file previously written by Record or WriteCorpus and
+// arranges for the next call to Value to return exactly the struct it
+// encodes, rather than generating a fresh one.  This lets a previously
+// discovered failing case be replayed deterministically.
+func (f *Fuzz) Seed(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	v, err := parseCorpusEntry(f.typ, f.fields, data)
+	if err != nil {
+		return err
+	}
+	f.seed = &v
+	return nil
+}
+
+// WriteCorpus generates n values from f using r and commits each as its own
+// file under testdata/fuzz/<testName>, keyed by a hash of its contents, in
+// the layout `go test -fuzz` expects for a seed corpus.  It returns the
+// paths written.
+func (f *Fuzz) WriteCorpus(testName string, r *rand.Rand, n int) ([]string, error) {
+	dir := filepath.Join("testdata", "fuzz", testName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	paths := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := f.Value(r, 0)
+		if err != nil {
+			return paths, err
+		}
+		var buf bytes.Buffer
+		if err := writeCorpusEntry(&buf, v); err != nil {
+			return paths, err
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		path := filepath.Join(dir, hex.EncodeToString(sum[:]))
+		if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+func writeCorpusEntry(w io.Writer, v reflect.Value) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, corpusVersion)
+	fmt.Fprintln(bw)
+	for i := 0; i < v.NumField(); i++ {
+		if err := writeCorpusValue(bw, v.Field(i)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// writeCorpusValue writes one line-oriented literal for v.  []byte,
+// string, bool, and numeric fields use the same literal syntax native Go
+// fuzz corpus files use.  Pointers, structs, slices, and maps — the
+// shapes chunk0-5's BindPath lets a bound field be — recurse through
+// formatCorpusValue into a single compound literal on that line; this
+// nested syntax isn't part of the native `go test -fuzz` format, but
+// round-trips through parseCorpusLiteral.  Any other kind is reported as
+// an error rather than silently dropped, so Record/WriteCorpus/
+// persistFailure don't claim success for a struct they can't reproduce.
+func writeCorpusValue(w *bufio.Writer, v reflect.Value) error {
+	lit, err := formatCorpusValue(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, lit)
+	return err
+}
+
+func formatCorpusValue(v reflect.Value) (string, error) {
+	switch {
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8:
+		return fmt.Sprintf("[]byte(%q)", v.Bytes()), nil
+	case v.Kind() == reflect.String:
+		return fmt.Sprintf("string(%q)", v.String()), nil
+	case v.Kind() == reflect.Bool:
+		return fmt.Sprintf("bool(%t)", v.Bool()), nil
+	case v.CanInt():
+		return fmt.Sprintf("%s(%d)", v.Type(), v.Int()), nil
+	case v.CanUint():
+		return fmt.Sprintf("%s(%d)", v.Type(), v.Uint()), nil
+	case v.CanFloat():
+		return fmt.Sprintf("%s(%v)", v.Type(), v.Float()), nil
+	case v.Kind() == reflect.Ptr:
+		if v.IsNil() {
+			return fmt.Sprintf("%s(nil)", v.Type()), nil
+		}
+		elem, err := formatCorpusValue(v.Elem())
+		if err != nil {
+			return "", err
+		}
+		return "*" + elem, nil
+	case v.Kind() == reflect.Slice:
+		parts := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			elem, err := formatCorpusValue(v.Index(i))
+			if err != nil {
+				return "", err
+			}
+			parts[i] = elem
+		}
+		return fmt.Sprintf("%s[%s]", v.Type(), strings.Join(parts, ";")), nil
+	case v.Kind() == reflect.Struct:
+		parts := make([]string, v.NumField())
+		for i := 0; i < v.NumField(); i++ {
+			elem, err := formatCorpusValue(v.Field(i))
+			if err != nil {
+				return "", err
+			}
+			parts[i] = elem
+		}
+		return fmt.Sprintf("%s{%s}", v.Type(), strings.Join(parts, ";")), nil
+	case v.Kind() == reflect.Map:
+		keys := v.MapKeys()
+		parts := make([]string, 0, len(keys))
+		for _, k := range keys {
+			ks, err := formatCorpusValue(k)
+			if err != nil {
+				return "", err
+			}
+			vs, err := formatCorpusValue(v.MapIndex(k))
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, ks+":"+vs)
+		}
+		return fmt.Sprintf("%s{%s}", v.Type(), strings.Join(parts, ";")), nil
+	default:
+		return "", fmt.Errorf("fuzz: unsupported field kind %s for corpus encoding", v.Kind())
+	}
+}
+
+// parseCorpusEntry reverses writeCorpusEntry, assigning each decoded literal
+// back to the struct field in fields with the matching declaration order.
+func parseCorpusEntry(typ reflect.Type, fields map[string]reflect.StructField, data []byte) (reflect.Value, error) {
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(lines) == 0 || lines[0] != corpusVersion {
+		return reflect.Value{}, errCorpusFormat
+	}
+
+	v := reflect.New(typ).Elem()
+	values := lines[1:]
+	idx := 0
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		for idx < len(values) && strings.TrimSpace(values[idx]) == "" {
+			idx++
+		}
+		if idx >= len(values) {
+			break
+		}
+		if _, ok := fields[field.Name]; !ok {
+			continue
+		}
+		lit := values[idx]
+		idx++
+		if err := parseCorpusLiteral(v.Field(i), lit); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+	return v, nil
+}
+
+// parseCorpusLiteral reverses formatCorpusValue.  dst's own Kind decides
+// how to parse, rather than sniffing lit's contents, so the dispatch to
+// the nested compound forms (pointer, slice, struct, map) is unambiguous
+// regardless of what the enclosed literals look like.
+func parseCorpusLiteral(dst reflect.Value, lit string) error {
+	switch dst.Kind() {
+	case reflect.Ptr:
+		return parsePtrCorpusLiteral(dst, lit)
+	case reflect.Slice:
+		if dst.Type().Elem().Kind() != reflect.Uint8 {
+			return parseSliceCorpusLiteral(dst, lit)
+		}
+	case reflect.Struct:
+		return parseStructCorpusLiteral(dst, lit)
+	case reflect.Map:
+		return parseMapCorpusLiteral(dst, lit)
+	}
+
+	open := strings.IndexByte(lit, '(')
+	shut := strings.LastIndexByte(lit, ')')
+	if open < 0 || shut < open {
+		return errCorpusFormat
+	}
+	typeName, raw := lit[:open], lit[open+1:shut]
+
+	switch {
+	case typeName == "string":
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetString(s)
+	case typeName == "[]byte":
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetBytes([]byte(s))
+	case typeName == "bool":
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		dst.SetBool(b)
+	case dst.CanInt():
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		dst.SetInt(n)
+	case dst.CanUint():
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			return err
+		}
+		dst.SetUint(n)
+	case dst.CanFloat():
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		dst.SetFloat(n)
+	default:
+		return errCorpusFormat
+	}
+	return nil
+}
+
+func parsePtrCorpusLiteral(dst reflect.Value, lit string) error {
+	if lit == dst.Type().String()+"(nil)" {
+		dst.Set(reflect.Zero(dst.Type()))
+		return nil
+	}
+	if !strings.HasPrefix(lit, "*") {
+		return errCorpusFormat
+	}
+	ptr := reflect.New(dst.Type().Elem())
+	if err := parseCorpusLiteral(ptr.Elem(), lit[1:]); err != nil {
+		return err
+	}
+	dst.Set(ptr)
+	return nil
+}
+
+func parseSliceCorpusLiteral(dst reflect.Value, lit string) error {
+	open := strings.IndexByte(lit, '[')
+	shut := strings.LastIndexByte(lit, ']')
+	if open < 0 || shut < open {
+		return errCorpusFormat
+	}
+	inner := lit[open+1 : shut]
+	if inner == "" {
+		dst.Set(reflect.MakeSlice(dst.Type(), 0, 0))
+		return nil
+	}
+	parts := splitTopLevel(inner, ';')
+	v := reflect.MakeSlice(dst.Type(), len(parts), len(parts))
+	for i, p := range parts {
+		if err := parseCorpusLiteral(v.Index(i), p); err != nil {
+			return err
+		}
+	}
+	dst.Set(v)
+	return nil
+}
+
+func parseStructCorpusLiteral(dst reflect.Value, lit string) error {
+	open := strings.IndexByte(lit, '{')
+	shut := strings.LastIndexByte(lit, '}')
+	if open < 0 || shut < open {
+		return errCorpusFormat
+	}
+	inner := lit[open+1 : shut]
+	if dst.NumField() == 0 {
+		if inner != "" {
+			return errCorpusFormat
+		}
+		return nil
+	}
+	parts := splitTopLevel(inner, ';')
+	if len(parts) != dst.NumField() {
+		return errCorpusFormat
+	}
+	for i, p := range parts {
+		if err := parseCorpusLiteral(dst.Field(i), p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseMapCorpusLiteral(dst reflect.Value, lit string) error {
+	open := strings.IndexByte(lit, '{')
+	shut := strings.LastIndexByte(lit, '}')
+	if open < 0 || shut < open {
+		return errCorpusFormat
+	}
+	inner := lit[open+1 : shut]
+	m := reflect.MakeMap(dst.Type())
+	if inner != "" {
+		for _, pair := range splitTopLevel(inner, ';') {
+			kv := splitTopLevel(pair, ':')
+			if len(kv) != 2 {
+				return errCorpusFormat
+			}
+			key := reflect.New(dst.Type().Key()).Elem()
+			if err := parseCorpusLiteral(key, kv[0]); err != nil {
+				return err
+			}
+			val := reflect.New(dst.Type().Elem()).Elem()
+			if err := parseCorpusLiteral(val, kv[1]); err != nil {
+				return err
+			}
+			m.SetMapIndex(key, val)
+		}
+	}
+	dst.Set(m)
+	return nil
+}
+
+// splitTopLevel splits s on sep, ignoring any sep found inside a quoted
+// string or nested (), [], or {} — the bracket forms formatCorpusValue
+// uses for pointers, slices, structs, and maps.
+func splitTopLevel(s string, sep byte) []string {
+	var out []string
+	depth := 0
+	inQuote := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' && (i == 0 || s[i-1] != '\\'):
+			inQuote = !inQuote
+		case inQuote:
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == sep && depth == 0:
+			out = append(out, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(out, s[start:])
+}
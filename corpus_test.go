@@ -0,0 +1,86 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestCorpusRoundTripsNonByteSlice(t *testing.T) {
+	type withSlice struct {
+		Items []int
+		Name  string
+	}
+	want := withSlice{Items: []int{1, 2, 3}, Name: "abc"}
+
+	var buf bytes.Buffer
+	if err := writeCorpusEntry(&buf, reflect.ValueOf(want)); err != nil {
+		t.Fatalf("writeCorpusEntry: %s", err)
+	}
+
+	fields := make(map[string]reflect.StructField)
+	typ := reflect.TypeOf(want)
+	for i := 0; i < typ.NumField(); i++ {
+		fields[typ.Field(i).Name] = typ.Field(i)
+	}
+	got, err := parseCorpusEntry(typ, fields, buf.Bytes())
+	if err != nil {
+		t.Fatalf("parseCorpusEntry: %s", err)
+	}
+	if !reflect.DeepEqual(got.Interface(), want) {
+		t.Fatalf("round trip: got %#v, want %#v", got.Interface(), want)
+	}
+}
+
+func TestCorpusRoundTripsNestedStructAndMap(t *testing.T) {
+	type inner struct {
+		N int
+	}
+	type withStruct struct {
+		Inner inner
+		Tags  map[string]int
+	}
+	want := withStruct{Inner: inner{N: 5}, Tags: map[string]int{"a": 1}}
+
+	var buf bytes.Buffer
+	if err := writeCorpusEntry(&buf, reflect.ValueOf(want)); err != nil {
+		t.Fatalf("writeCorpusEntry: %s", err)
+	}
+
+	fields := make(map[string]reflect.StructField)
+	typ := reflect.TypeOf(want)
+	for i := 0; i < typ.NumField(); i++ {
+		fields[typ.Field(i).Name] = typ.Field(i)
+	}
+	got, err := parseCorpusEntry(typ, fields, buf.Bytes())
+	if err != nil {
+		t.Fatalf("parseCorpusEntry: %s", err)
+	}
+	if !reflect.DeepEqual(got.Interface(), want) {
+		t.Fatalf("round trip: got %#v, want %#v", got.Interface(), want)
+	}
+}
+
+func TestWriteCorpusValueErrorsOnUnsupportedKind(t *testing.T) {
+	type withChan struct {
+		C chan int
+	}
+	var buf bytes.Buffer
+	if err := writeCorpusEntry(&buf, reflect.ValueOf(withChan{C: make(chan int)})); err == nil {
+		t.Fatal("writeCorpusEntry: expected an error for an unsupported field kind, got nil")
+	}
+}
@@ -31,15 +31,18 @@ var (
 	errUnmatchedBinding = errors.New("fuzz: unmatched binding")
 	errDuplBinding      = errors.New("fuzz: duplicated binding")
 	errIllegalGen       = errors.New("fuzz: illegal generator")
+	errNotFunc          = errors.New("fuzz: requested type is not a func")
 )
 
 // Fuzz describes a context in which a given struct's fields are to be
 // annotated.
 type Fuzz struct {
 	bindings             map[string]Generator
+	paths                map[string]Generator
 	zeroValueFallthrough bool
 	typ                  reflect.Type
-	fields map[string]reflect.StructField
+	fields               map[string]reflect.StructField
+	seed                 *reflect.Value
 }
 
 type option func(*Fuzz) (option, error)
@@ -121,6 +124,10 @@ func (f *Fuzz) Value(r *rand.Rand, n int) (v reflect.Value, err error) {
 			}
 		}
 	}()
+	if f.seed != nil {
+		v, f.seed = *f.seed, nil
+		return v, nil
+	}
 	v = reflect.New(f.typ).Elem()
 	for name, field := range f.fields {
 		gen, ok := f.bindings[name]
@@ -131,6 +138,12 @@ func (f *Fuzz) Value(r *rand.Rand, n int) (v reflect.Value, err error) {
 				return v, err
 			}
 			v.FieldByName(name).Set(elem)
+		case len(f.paths) > 0 && (f.paths[name] != nil || hasPathsUnder(f.paths, name)):
+			elem, err := f.pathValue(name, field.Type, r, n)
+			if err != nil {
+				return v, err
+			}
+			v.FieldByName(name).Set(elem)
 		case f.zeroValueFallthrough:
 			continue
 		default:
@@ -153,7 +166,7 @@ func New(t reflect.Type) (*Fuzz, error) {
 	for i := 0; i < t.NumField(); i++ {
 		fields[t.Field(i).Name] = t.Field(i)
 	}
-	return &Fuzz{typ: t, bindings: make(map[string]Generator), fields: fields}, nil
+	return &Fuzz{typ: t, bindings: make(map[string]Generator), paths: make(map[string]Generator), fields: fields}, nil
 }
 
 // Must wraps New invocations to ensure that errors are caught at initialization
@@ -184,10 +197,12 @@ func QuickGenerator(g Generator) quick.Generator {
 
 // The QuickValues type is an adaptor to allow the use of Generator as
 // testing/quick's Config.Values.  It panics if an error occurs in the
-// stack.
+// stack, or if the number of generators doesn't match the number of values
+// requested by quick.Check's target function.  Prefer QuickValuesFor, which
+// catches that mismatch at construction time instead of mid-run.
 func QuickValues(g ...Generator) func([]reflect.Value, *rand.Rand) {
 	return func(v []reflect.Value, r *rand.Rand) {
-		if len(v) != len(v) {
+		if len(v) != len(g) {
 			panic("fuzz: incongruent Values() and Generator... signature")
 		}
 		for i, g := range g {
@@ -200,6 +215,67 @@ func QuickValues(g ...Generator) func([]reflect.Value, *rand.Rand) {
 	}
 }
 
+// NamedGen binds a Generator to a target function's parameter at Index.
+type NamedGen struct {
+	Index int
+	Gen   Generator
+}
+
+// TypedGenerator is optionally implemented by a Generator to declare its
+// result type in advance, letting QuickValuesFor validate assignability at
+// construction time rather than failing when the Generator is finally
+// invoked.
+type TypedGenerator interface {
+	Generator
+	Type() reflect.Type
+}
+
+// QuickValuesFor inspects fnType, the type of the function under test, and
+// returns a testing/quick Config.Values function that generates each
+// parameter using the Generator bound to its position in bindings, falling
+// back to quick.Value for any parameter left unbound.  Unlike QuickValues,
+// it validates at construction time that fnType is a func, that no
+// binding's Index is out of range or duplicated, and, for any binding whose
+// Generator implements TypedGenerator, that its declared type is assignable
+// to the corresponding parameter.
+func QuickValuesFor(fnType reflect.Type, bindings ...NamedGen) (func([]reflect.Value, *rand.Rand), error) {
+	if fnType == nil || fnType.Kind() != reflect.Func {
+		return nil, errNotFunc
+	}
+	gens := make([]Generator, fnType.NumIn())
+	for _, b := range bindings {
+		if b.Index < 0 || b.Index >= fnType.NumIn() {
+			return nil, fmt.Errorf("fuzz: binding index %d out of range for %s", b.Index, fnType)
+		}
+		if gens[b.Index] != nil {
+			return nil, errDuplBinding
+		}
+		if tg, ok := b.Gen.(TypedGenerator); ok {
+			if !tg.Type().AssignableTo(fnType.In(b.Index)) {
+				return nil, fmt.Errorf("fuzz: generator for parameter %d produces %s, not assignable to %s", b.Index, tg.Type(), fnType.In(b.Index))
+			}
+		}
+		gens[b.Index] = b.Gen
+	}
+	return func(v []reflect.Value, r *rand.Rand) {
+		for i := range v {
+			if gens[i] != nil {
+				elem, err := gens[i].Generate(r, 0)
+				if err != nil {
+					panic(fmt.Errorf("fuzz: %s", err))
+				}
+				v[i] = elem
+				continue
+			}
+			elem, ok := quick.Value(fnType.In(i), r)
+			if !ok {
+				panic(errIllegal)
+			}
+			v[i] = elem
+		}
+	}, nil
+}
+
 // Generator creates types per a user-provided policy.
 type Generator interface {
 	// Generate emits a generated value for the provided random r and size hint n.
@@ -0,0 +1,76 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestQuickValuesFillsEveryValue(t *testing.T) {
+	fn := QuickValues(IntGenerator{Prob: 1}, StringGenerator{Prob: 1})
+	v := make([]reflect.Value, 2)
+	fn(v, rand.New(rand.NewSource(1)))
+	if v[0].Kind() != reflect.Int {
+		t.Fatalf("v[0]: got kind %s, want int", v[0].Kind())
+	}
+	if v[1].Kind() != reflect.String {
+		t.Fatalf("v[1]: got kind %s, want string", v[1].Kind())
+	}
+}
+
+func TestQuickValuesPanicsOnLengthMismatch(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic on generator/value count mismatch")
+		}
+	}()
+	fn := QuickValues(IntGenerator{})
+	v := make([]reflect.Value, 2)
+	fn(v, rand.New(rand.NewSource(1)))
+}
+
+func TestQuickValuesForBindsByIndexAndFallsThrough(t *testing.T) {
+	fnType := reflect.TypeOf(func(int, string) bool { return false })
+	values, err := QuickValuesFor(fnType, NamedGen{Index: 0, Gen: IntGenerator{Typ: reflect.TypeOf(int(0)), Prob: 1}})
+	if err != nil {
+		t.Fatalf("QuickValuesFor: %s", err)
+	}
+	v := make([]reflect.Value, 2)
+	values(v, rand.New(rand.NewSource(1)))
+	if v[0].Kind() != reflect.Int {
+		t.Fatalf("v[0]: got kind %s, want int", v[0].Kind())
+	}
+	if v[1].Kind() != reflect.String {
+		t.Fatalf("v[1]: got kind %s, want string (fallthrough to quick.Value)", v[1].Kind())
+	}
+}
+
+func TestQuickValuesForRejectsTypeMismatch(t *testing.T) {
+	fnType := reflect.TypeOf(func(string) bool { return false })
+	_, err := QuickValuesFor(fnType, NamedGen{Index: 0, Gen: IntGenerator{Typ: reflect.TypeOf(int(0))}})
+	if err == nil {
+		t.Fatal("QuickValuesFor: expected an error for an int generator bound to a string parameter")
+	}
+}
+
+func TestQuickValuesForRejectsOutOfRangeIndex(t *testing.T) {
+	fnType := reflect.TypeOf(func(int) bool { return false })
+	_, err := QuickValuesFor(fnType, NamedGen{Index: 1, Gen: IntGenerator{}})
+	if err == nil {
+		t.Fatal("QuickValuesFor: expected an error for an out-of-range index")
+	}
+}
@@ -0,0 +1,272 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"math"
+	"math/rand"
+	"reflect"
+	"strings"
+)
+
+// defaultSpecialProb is the probability with which the generators in this
+// file return a value from their special-values pool, absent an explicit
+// Prob.
+const defaultSpecialProb = 0.2
+
+// specialInts is the curated pool of boundary integers IntGenerator draws
+// from: 0, ±1, and ±(2^k-1), ±2^k, ±(2^k+1) for k in {7, 8, 15, 16, 31, 32,
+// 63}.  These are the bit-width boundaries most likely to trip up
+// off-by-one and overflow bugs that uniform random sampling rarely hits.
+var specialInts = buildSpecialInts()
+
+func buildSpecialInts() []int64 {
+	out := []int64{0, 1, -1}
+	for _, k := range []uint{7, 8, 15, 16, 31, 32, 63} {
+		p := int64(1) << k
+		for _, v := range [...]int64{p - 1, p, p + 1} {
+			out = append(out, v, -v)
+		}
+	}
+	return out
+}
+
+// specialUints is the unsigned counterpart of specialInts.
+var specialUints = buildSpecialUints()
+
+func buildSpecialUints() []uint64 {
+	out := []uint64{0, 1}
+	for _, k := range []uint{7, 8, 15, 16, 31, 32, 63} {
+		p := uint64(1) << k
+		out = append(out, p-1, p, p+1)
+	}
+	return out
+}
+
+// specialFloats is the curated pool of boundary floats Float32Generator and
+// Float64Generator draw from.
+var specialFloats = []float64{
+	0, math.Copysign(0, -1), math.NaN(), math.Inf(1), math.Inf(-1),
+	math.SmallestNonzeroFloat64, math.MaxFloat64, -math.MaxFloat64, 1, -1,
+}
+
+// specialStrings is the curated pool of boundary strings StringGenerator
+// draws from: empty, a single byte, invalid UTF-8, a long ASCII run, an
+// embedded NUL, and a combining character.
+var specialStrings = []string{
+	"",
+	"\x00",
+	"a",
+	"\xff",
+	"\xc3\x28",
+	strings.Repeat("a", 4096),
+	"a\x00b",
+	"á",
+}
+
+// prob resolves the effective special-value injection probability for a
+// generator's Prob/Disabled pair: Disabled always means never inject,
+// otherwise Prob<=0 means "use the default", since a float64 zero value
+// can't otherwise be told apart from an unset Prob.
+func prob(p float64, disabled bool) float64 {
+	if disabled {
+		return 0
+	}
+	if p <= 0 {
+		return defaultSpecialProb
+	}
+	return p
+}
+
+var intType = reflect.TypeOf(int(0))
+
+// IntGenerator biases toward boundary int values rather than the uniform
+// distribution quick.Value gives.  With probability Prob (0 defaults to
+// 20%) it returns a value from specialInts, converted to Typ; otherwise it
+// falls through to a uniformly random value of Typ.  Set Disabled to opt
+// out of special-value injection entirely (Prob: 0 alone still means "use
+// the default"). Bind it to a field with BindField to opt a single field
+// into boundary-biased fuzzing; set Typ to the field's exact int type
+// (e.g. reflect.TypeOf(int32(0))) when it isn't plain int, or Generate
+// will produce a value the field can't accept.
+type IntGenerator struct {
+	Typ      reflect.Type
+	Prob     float64
+	Disabled bool
+}
+
+// Type implements TypedGenerator.
+func (g IntGenerator) Type() reflect.Type {
+	if g.Typ != nil {
+		return g.Typ
+	}
+	return intType
+}
+
+func (g IntGenerator) Generate(r *rand.Rand, n int) (reflect.Value, error) {
+	t := g.Type()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+	default:
+		return reflect.Value{}, errIllegalGen
+	}
+	var v int64
+	if r.Float64() < prob(g.Prob, g.Disabled) {
+		v = specialInts[r.Intn(len(specialInts))]
+	} else {
+		v = int64(r.Int())
+	}
+	return reflect.ValueOf(v).Convert(t), nil
+}
+
+var uintType = reflect.TypeOf(uint(0))
+
+// UintGenerator is the unsigned counterpart of IntGenerator, drawing from
+// specialUints and converting to Typ (plain uint if unset).
+type UintGenerator struct {
+	Typ      reflect.Type
+	Prob     float64
+	Disabled bool
+}
+
+// Type implements TypedGenerator.
+func (g UintGenerator) Type() reflect.Type {
+	if g.Typ != nil {
+		return g.Typ
+	}
+	return uintType
+}
+
+func (g UintGenerator) Generate(r *rand.Rand, n int) (reflect.Value, error) {
+	t := g.Type()
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+	default:
+		return reflect.Value{}, errIllegalGen
+	}
+	var v uint64
+	if r.Float64() < prob(g.Prob, g.Disabled) {
+		v = specialUints[r.Intn(len(specialUints))]
+	} else {
+		v = uint64(r.Int63())
+	}
+	return reflect.ValueOf(v).Convert(t), nil
+}
+
+// Float32Generator biases toward boundary float32 values (0, ±0, NaN,
+// ±Inf, the smallest subnormal, ±MaxFloat, ±1) rather than the uniform
+// distribution quick.Value gives.  Set Disabled to opt out of
+// special-value injection entirely (Prob: 0 alone still means "use the
+// default").
+type Float32Generator struct {
+	Prob     float64
+	Disabled bool
+}
+
+func (g Float32Generator) Generate(r *rand.Rand, n int) (reflect.Value, error) {
+	if r.Float64() < prob(g.Prob, g.Disabled) {
+		return reflect.ValueOf(float32(specialFloats[r.Intn(len(specialFloats))])), nil
+	}
+	return reflect.ValueOf(float32(r.NormFloat64())), nil
+}
+
+// Float64Generator is the float64 counterpart of Float32Generator.
+type Float64Generator struct {
+	Prob     float64
+	Disabled bool
+}
+
+func (g Float64Generator) Generate(r *rand.Rand, n int) (reflect.Value, error) {
+	if r.Float64() < prob(g.Prob, g.Disabled) {
+		return reflect.ValueOf(specialFloats[r.Intn(len(specialFloats))]), nil
+	}
+	return reflect.ValueOf(r.NormFloat64()), nil
+}
+
+// StringGenerator biases toward boundary strings (empty, a single byte,
+// invalid UTF-8, a long ASCII run, an embedded NUL, a combining character)
+// rather than the uniform distribution quick.Value gives.  Set Disabled
+// to opt out of special-value injection entirely (Prob: 0 alone still
+// means "use the default").
+type StringGenerator struct {
+	Prob     float64
+	Disabled bool
+}
+
+func (g StringGenerator) Generate(r *rand.Rand, n int) (reflect.Value, error) {
+	if r.Float64() < prob(g.Prob, g.Disabled) {
+		return reflect.ValueOf(specialStrings[r.Intn(len(specialStrings))]), nil
+	}
+	return reflect.ValueOf(randomASCII(r, n)), nil
+}
+
+func randomASCII(r *rand.Rand, n int) string {
+	if n <= 0 {
+		n = 32
+	}
+	b := make([]byte, r.Intn(n+1))
+	for i := range b {
+		b[i] = byte('a' + r.Intn(26))
+	}
+	return string(b)
+}
+
+// SliceGenerator biases toward structurally interesting slice lengths —
+// nil, empty, length-1, and very large — rather than the uniform
+// distribution quick.Value gives.  Typ must be the target slice type (e.g.
+// reflect.TypeOf([]int{})) and Elem generates each element.  Set Disabled
+// to opt out of the length-bias entirely (Prob: 0 alone still means "use
+// the default").
+type SliceGenerator struct {
+	Typ      reflect.Type
+	Elem     Generator
+	Prob     float64
+	Disabled bool
+}
+
+func (g SliceGenerator) Generate(r *rand.Rand, n int) (reflect.Value, error) {
+	if g.Typ == nil || g.Typ.Kind() != reflect.Slice {
+		return reflect.Value{}, errIllegalGen
+	}
+	if r.Float64() < prob(g.Prob, g.Disabled) {
+		switch r.Intn(4) {
+		case 0:
+			return reflect.Zero(g.Typ), nil
+		case 1:
+			return reflect.MakeSlice(g.Typ, 0, 0), nil
+		case 2:
+			return g.makeLen(r, n, 1)
+		default:
+			return g.makeLen(r, n, 256)
+		}
+	}
+	size := n
+	if size <= 0 {
+		size = 8
+	}
+	return g.makeLen(r, n, r.Intn(size+1))
+}
+
+func (g SliceGenerator) makeLen(r *rand.Rand, n, length int) (reflect.Value, error) {
+	v := reflect.MakeSlice(g.Typ, length, length)
+	for i := 0; i < length; i++ {
+		elem, err := g.Elem.Generate(r, n)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Index(i).Set(elem)
+	}
+	return v, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestIntGeneratorBindFieldNarrowerThanInt(t *testing.T) {
+	type s struct {
+		X int32
+		Y uint8
+	}
+	typ := reflect.TypeOf(s{})
+	f := Must(New(typ))
+	if _, err := f.Option(
+		BindField("X", IntGenerator{Typ: reflect.TypeOf(int32(0)), Prob: 1}),
+		BindField("Y", UintGenerator{Typ: reflect.TypeOf(uint8(0)), Prob: 1}),
+	); err != nil {
+		t.Fatalf("Option: %s", err)
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		if _, err := f.Value(r, 0); err != nil {
+			t.Fatalf("Value: %s", err)
+		}
+	}
+}
+
+func TestIntGeneratorDefaultsToInt(t *testing.T) {
+	g := IntGenerator{}
+	r := rand.New(rand.NewSource(1))
+	v, err := g.Generate(r, 0)
+	if err != nil {
+		t.Fatalf("Generate: %s", err)
+	}
+	if v.Type() != reflect.TypeOf(int(0)) {
+		t.Fatalf("Generate: got type %s, want int", v.Type())
+	}
+}
+
+func TestIntGeneratorDisabledNeverInjectsSpecialValues(t *testing.T) {
+	g := IntGenerator{Prob: 1, Disabled: true}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		v, err := g.Generate(r, 0)
+		if err != nil {
+			t.Fatalf("Generate: %s", err)
+		}
+		n := v.Int()
+		for _, special := range specialInts {
+			if n == special {
+				t.Fatalf("Generate: got special value %d with Disabled set", n)
+			}
+		}
+	}
+}
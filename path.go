@@ -0,0 +1,171 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing/quick"
+)
+
+// BindPath attaches a generator to a path into the session's struct type,
+// reaching where BindField cannot: nested struct fields ("Inner.Addr.Street"),
+// every entry of a map field ("Tags[*]"), and every element of a slice field,
+// optionally addressing a field within it ("Items[]", "Items[].Name").  New
+// builds a recursive sub-session for each struct-typed field or element
+// encountered along a bound path, honoring UseZeroValueFallthrough at every
+// level for any field the path doesn't reach.
+func BindPath(path string, gen Generator) option {
+	return func(f *Fuzz) (option, error) {
+		if _, ok := f.paths[path]; ok {
+			return nil, errDuplBinding
+		}
+		f.paths[path] = gen
+		return UnbindPath(path), nil
+	}
+}
+
+// UnbindPath removes a generator bound with BindPath.
+func UnbindPath(path string) option {
+	return func(f *Fuzz) (option, error) {
+		gen, ok := f.paths[path]
+		if !ok {
+			return nil, fmt.Errorf("fuzz: absent binding %s", path)
+		}
+		delete(f.paths, path)
+		return BindPath(path, gen), nil
+	}
+}
+
+// hasPathsUnder reports whether any bound path descends into prefix, i.e.
+// addresses a struct field, slice element, or map entry reached through it.
+func hasPathsUnder(paths map[string]Generator, prefix string) bool {
+	for k := range paths {
+		if strings.HasPrefix(k, prefix+".") || strings.HasPrefix(k, prefix+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// pathValue generates a value of typ reached by path, consulting f.paths
+// for an exact match first and otherwise recursing through structs,
+// pointers, slices, and maps so that any deeper bound path is honored.
+// Fields with no binding and no bound path beneath them fall through to
+// quick.Value, or the zero value if f.zeroValueFallthrough is set.
+func (f *Fuzz) pathValue(path string, typ reflect.Type, r *rand.Rand, n int) (reflect.Value, error) {
+	if gen, ok := f.paths[path]; ok {
+		return gen.Generate(r, n)
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		elem, err := f.pathValue(path, typ.Elem(), r, n)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(typ.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Struct:
+		return f.structPathValue(path, typ, r, n)
+	case reflect.Slice:
+		return f.slicePathValue(path, typ, r, n)
+	case reflect.Map:
+		return f.mapPathValue(path, typ, r, n)
+	default:
+		if f.zeroValueFallthrough {
+			return reflect.Zero(typ), nil
+		}
+		elem, ok := quick.Value(typ, r)
+		if !ok {
+			return reflect.Value{}, errIllegal
+		}
+		return elem, nil
+	}
+}
+
+func (f *Fuzz) structPathValue(path string, typ reflect.Type, r *rand.Rand, n int) (reflect.Value, error) {
+	v := reflect.New(typ).Elem()
+	prefix := path
+	if prefix != "" {
+		prefix += "."
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		childPath := prefix + field.Name
+		switch {
+		case f.paths[childPath] != nil || hasPathsUnder(f.paths, childPath):
+			elem, err := f.pathValue(childPath, field.Type, r, n)
+			if err != nil {
+				return reflect.Value{}, err
+			}
+			v.Field(i).Set(elem)
+		case f.zeroValueFallthrough:
+			continue
+		default:
+			elem, ok := quick.Value(field.Type, r)
+			if !ok {
+				return reflect.Value{}, errIllegal
+			}
+			v.Field(i).Set(elem)
+		}
+	}
+	return v, nil
+}
+
+func (f *Fuzz) slicePathValue(path string, typ reflect.Type, r *rand.Rand, n int) (reflect.Value, error) {
+	length := n
+	if length <= 0 {
+		length = 5
+	}
+	length = r.Intn(length + 1)
+
+	v := reflect.MakeSlice(typ, length, length)
+	elemPath := path + "[]"
+	for i := 0; i < length; i++ {
+		elem, err := f.pathValue(elemPath, typ.Elem(), r, n)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.Index(i).Set(elem)
+	}
+	return v, nil
+}
+
+func (f *Fuzz) mapPathValue(path string, typ reflect.Type, r *rand.Rand, n int) (reflect.Value, error) {
+	length := n
+	if length <= 0 {
+		length = 5
+	}
+	length = r.Intn(length + 1)
+
+	v := reflect.MakeMap(typ)
+	valPath := path + "[*]"
+	for i := 0; i < length; i++ {
+		key, ok := quick.Value(typ.Key(), r)
+		if !ok {
+			return reflect.Value{}, errIllegal
+		}
+		val, err := f.pathValue(valPath, typ.Elem(), r, n)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		v.SetMapIndex(key, val)
+	}
+	return v, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestBindPathNestedStruct(t *testing.T) {
+	type addr struct {
+		Street string
+	}
+	type inner struct {
+		Addr addr
+	}
+	type outer struct {
+		Inner inner
+	}
+	f := Must(New(reflect.TypeOf(outer{})))
+	if _, err := f.Option(BindPath("Inner.Addr.Street", StringGenerator{Prob: 1})); err != nil {
+		t.Fatalf("Option: %s", err)
+	}
+	v, err := f.Value(rand.New(rand.NewSource(1)), 0)
+	if err != nil {
+		t.Fatalf("Value: %s", err)
+	}
+	got := v.Interface().(outer).Inner.Addr.Street
+	found := false
+	for _, s := range specialStrings {
+		if got == s {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("Value: Street = %q, want a value from specialStrings", got)
+	}
+}
+
+func TestBindPathSliceElements(t *testing.T) {
+	type item struct {
+		Name string
+	}
+	type holder struct {
+		Items []item
+	}
+	f := Must(New(reflect.TypeOf(holder{})))
+	if _, err := f.Option(BindPath("Items[].Name", StringGenerator{Prob: 1})); err != nil {
+		t.Fatalf("Option: %s", err)
+	}
+	v, err := f.Value(rand.New(rand.NewSource(1)), 3)
+	if err != nil {
+		t.Fatalf("Value: %s", err)
+	}
+	items := v.Interface().(holder).Items
+	for _, it := range items {
+		found := false
+		for _, s := range specialStrings {
+			if it.Name == s {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("Value: Items[].Name = %q, want a value from specialStrings", it.Name)
+		}
+	}
+}
+
+func TestBindPathMapValues(t *testing.T) {
+	type holder struct {
+		Tags map[string]int
+	}
+	f := Must(New(reflect.TypeOf(holder{})))
+	if _, err := f.Option(BindPath("Tags[*]", IntGenerator{Prob: 1})); err != nil {
+		t.Fatalf("Option: %s", err)
+	}
+	v, err := f.Value(rand.New(rand.NewSource(1)), 3)
+	if err != nil {
+		t.Fatalf("Value: %s", err)
+	}
+	for _, n := range v.Interface().(holder).Tags {
+		found := false
+		for _, s := range specialInts {
+			if int64(n) == s {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("Value: Tags[*] = %d, want a value from specialInts", n)
+		}
+	}
+}
+
+func TestBindPathPointerField(t *testing.T) {
+	type inner struct {
+		N int
+	}
+	type holder struct {
+		Ptr *inner
+	}
+	f := Must(New(reflect.TypeOf(holder{})))
+	if _, err := f.Option(BindPath("Ptr.N", IntGenerator{Prob: 1})); err != nil {
+		t.Fatalf("Option: %s", err)
+	}
+	v, err := f.Value(rand.New(rand.NewSource(1)), 0)
+	if err != nil {
+		t.Fatalf("Value: %s", err)
+	}
+	ptr := v.Interface().(holder).Ptr
+	if ptr == nil {
+		t.Fatal("Value: Ptr is nil, want a populated pointer")
+	}
+}
@@ -0,0 +1,405 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+)
+
+// Shrinker produces candidate values that are "smaller" than v, for use when
+// minimizing a failing input discovered by Check.  Candidates are tried in
+// the order returned, and the first one that still reproduces the failure is
+// accepted; implementations should order them from most- to
+// least-aggressive.
+type Shrinker interface {
+	Shrink(v reflect.Value) []reflect.Value
+}
+
+// ShrinkerFunc is an adaptor to allow the use of ordinary functions as
+// Shrinkers.
+type ShrinkerFunc func(v reflect.Value) []reflect.Value
+
+func (s ShrinkerFunc) Shrink(v reflect.Value) []reflect.Value {
+	return s(v)
+}
+
+var intShrinker = ShrinkerFunc(func(v reflect.Value) []reflect.Value {
+	n := v.Int()
+	if n == 0 {
+		return nil
+	}
+	cands := make([]int64, 0, 3)
+	cands = append(cands, 0, n/2)
+	if n > 0 {
+		cands = append(cands, n-1)
+	} else {
+		cands = append(cands, n+1)
+	}
+	out := make([]reflect.Value, 0, len(cands))
+	for _, c := range cands {
+		nv := reflect.New(v.Type()).Elem()
+		nv.SetInt(c)
+		out = append(out, nv)
+	}
+	return out
+})
+
+var uintShrinker = ShrinkerFunc(func(v reflect.Value) []reflect.Value {
+	n := v.Uint()
+	if n == 0 {
+		return nil
+	}
+	cands := []uint64{0, n / 2, n - 1}
+	out := make([]reflect.Value, 0, len(cands))
+	for _, c := range cands {
+		nv := reflect.New(v.Type()).Elem()
+		nv.SetUint(c)
+		out = append(out, nv)
+	}
+	return out
+})
+
+var floatShrinker = ShrinkerFunc(func(v reflect.Value) []reflect.Value {
+	n := v.Float()
+	if n == 0 {
+		return nil
+	}
+	cands := []float64{0, n / 2}
+	out := make([]reflect.Value, 0, len(cands))
+	for _, c := range cands {
+		nv := reflect.New(v.Type()).Elem()
+		nv.SetFloat(c)
+		out = append(out, nv)
+	}
+	return out
+})
+
+var boolShrinker = ShrinkerFunc(func(v reflect.Value) []reflect.Value {
+	if !v.Bool() {
+		return nil
+	}
+	nv := reflect.New(v.Type()).Elem()
+	nv.SetBool(false)
+	return []reflect.Value{nv}
+})
+
+var stringShrinker = ShrinkerFunc(func(v reflect.Value) []reflect.Value {
+	s := v.String()
+	if len(s) == 0 {
+		return nil
+	}
+	cands := []string{"", s[:len(s)/2], s[1:]}
+	out := make([]reflect.Value, 0, len(cands))
+	for _, c := range cands {
+		nv := reflect.New(v.Type()).Elem()
+		nv.SetString(c)
+		out = append(out, nv)
+	}
+	return out
+})
+
+var sliceShrinker = ShrinkerFunc(func(v reflect.Value) []reflect.Value {
+	n := v.Len()
+	if n == 0 {
+		return nil
+	}
+	var out []reflect.Value
+	out = append(out, reflect.MakeSlice(v.Type(), 0, 0))
+	out = append(out, v.Slice(0, n/2))
+	out = append(out, v.Slice(1, n))
+	return out
+})
+
+// builtinShrinker returns the built-in Shrinker for t, or nil if t has no
+// built-in shrinking strategy.
+func builtinShrinker(t reflect.Type) Shrinker {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intShrinker
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uintShrinker
+	case reflect.Float32, reflect.Float64:
+		return floatShrinker
+	case reflect.String:
+		return stringShrinker
+	case reflect.Slice:
+		return sliceShrinker
+	case reflect.Bool:
+		return boolShrinker
+	}
+	return nil
+}
+
+// shrinkerFor prefers a Generator that also implements Shrinker, falling
+// back to the built-in shrinker for its type, if any.
+func shrinkerFor(gen Generator, t reflect.Type) Shrinker {
+	if s, ok := gen.(Shrinker); ok {
+		return s
+	}
+	return builtinShrinker(t)
+}
+
+// structuralCandidates produces shrink candidates for the value at path,
+// recursing into the kinds BindPath can address: pointers, structs, and
+// slices.  At every level it first checks f.paths[path] for a
+// BindPath-bound Generator that also implements Shrinker (or has a
+// built-in shrinker for its type), the same way shrinkerFor does for
+// f.bindings; only once that comes up empty does it fall back to
+// structural reduction (nil, zero-length, fewer/simpler elements), so a
+// custom Shrinker bound via BindPath to a nested field is actually used
+// instead of always being shadowed by the generic recursion.
+func (f *Fuzz) structuralCandidates(path string, v reflect.Value) []reflect.Value {
+	if shrinker := shrinkerFor(f.paths[path], v.Type()); shrinker != nil {
+		return shrinker.Shrink(v)
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil
+		}
+		out := []reflect.Value{reflect.Zero(v.Type())}
+		for _, c := range f.structuralCandidates(path, v.Elem()) {
+			np := reflect.New(v.Type().Elem())
+			np.Elem().Set(c)
+			out = append(out, np)
+		}
+		return out
+	case reflect.Struct:
+		var out []reflect.Value
+		t := v.Type()
+		prefix := path
+		if prefix != "" {
+			prefix += "."
+		}
+		for i := 0; i < t.NumField(); i++ {
+			fv := v.Field(i)
+			childPath := prefix + t.Field(i).Name
+			for _, c := range f.structuralCandidates(childPath, fv) {
+				nv := reflect.New(t).Elem()
+				nv.Set(v)
+				nv.Field(i).Set(c)
+				out = append(out, nv)
+			}
+		}
+		return out
+	case reflect.Slice:
+		n := v.Len()
+		if n == 0 {
+			return nil
+		}
+		out := []reflect.Value{reflect.MakeSlice(v.Type(), 0, 0), v.Slice(0, n/2), v.Slice(1, n)}
+		elemPath := path + "[]"
+		for i := 0; i < n; i++ {
+			for _, c := range f.structuralCandidates(elemPath, v.Index(i)) {
+				nv := reflect.MakeSlice(v.Type(), n, n)
+				reflect.Copy(nv, v)
+				nv.Index(i).Set(c)
+				out = append(out, nv)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+// CheckConfig configures a Check invocation.
+type CheckConfig struct {
+	// Rand supplies randomness for input generation.  If nil, a
+	// deterministic default source is used.
+	Rand *rand.Rand
+	// N bounds the number of generation attempts made before giving up
+	// without finding a failure.  Defaults to 100.
+	N int
+	// Size is the size hint passed through to Value and Generate calls.
+	Size int
+	// MaxShrinkSteps bounds the number of shrink iterations performed once
+	// a failure is found, guarding against runaway shrinkers.  Defaults to
+	// 1000.
+	MaxShrinkSteps int
+	// TestName identifies the testdata/fuzz/<TestName> directory the
+	// minimized failure is persisted under.  Defaults to "Check".
+	TestName string
+}
+
+// FailureReport describes a minimized failing input discovered by Check.
+type FailureReport struct {
+	// Value is the locally-minimal struct value that still reproduces the
+	// failure.
+	Value reflect.Value
+	// Err is the error returned (or panic value, wrapped) by the property
+	// predicate for Value.
+	Err error
+	// Steps is the number of shrink iterations performed to reach Value.
+	Steps int
+	// SeedPath is the testdata file the failure was persisted to, or empty
+	// if persistence failed.
+	SeedPath string
+}
+
+// Check repeatedly generates values of f's struct type and applies fn to
+// each, looking for an input on which fn returns a non-nil error (a panic in
+// fn is treated the same as a returned error).  Once a failing input is
+// found, Check shrinks it field-by-field, substituting each field with
+// progressively "smaller" candidates from its Shrinker and keeping any
+// substitution that still fails, until a full pass over all fields yields no
+// further reduction.  The minimized value is persisted under
+// testdata/fuzz/<TestName> so a later run can replay it.
+//
+// Check returns (nil, nil) if no failure is found within cfg.N attempts.
+func (f *Fuzz) Check(fn func(v reflect.Value) error, cfg *CheckConfig) (*FailureReport, error) {
+	if cfg == nil {
+		cfg = &CheckConfig{}
+	}
+	r := cfg.Rand
+	if r == nil {
+		r = rand.New(rand.NewSource(1))
+	}
+	n := cfg.N
+	if n <= 0 {
+		n = 100
+	}
+	testName := cfg.TestName
+	if testName == "" {
+		testName = "Check"
+	}
+
+	var failing reflect.Value
+	var failErr error
+	found := false
+	for i := 0; i < n; i++ {
+		v, err := f.Value(r, cfg.Size)
+		if err != nil {
+			return nil, err
+		}
+		if ferr := applyPredicate(fn, v); ferr != nil {
+			failing, failErr, found = v, ferr, true
+			break
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+
+	maxSteps := cfg.MaxShrinkSteps
+	if maxSteps <= 0 {
+		maxSteps = 1000
+	}
+	minimal, steps := f.shrink(failing, fn, maxSteps)
+
+	report := &FailureReport{Value: minimal, Err: failErr, Steps: steps}
+	if path, err := f.persistFailure(testName, minimal); err == nil {
+		report.SeedPath = path
+	}
+	return report, nil
+}
+
+// shrink reduces v field-by-field, keeping any substitution that still makes
+// fn fail, until a full pass over all fields yields no reduction or
+// maxSteps shrink attempts have been made.  Fields reached by a BindPath
+// binding (chunk0-5's nested structs, slices, and pointers) fall back to
+// structuralCandidates, which consults f.paths for a custom Shrinker at
+// every nesting level the same way shrinkerFor does for f.bindings, before
+// falling back to structural reduction so nested values still minimize.
+func (f *Fuzz) shrink(v reflect.Value, fn func(reflect.Value) error, maxSteps int) (reflect.Value, int) {
+	steps := 0
+	for steps < maxSteps {
+		reduced := false
+		for name := range f.fields {
+			fv := v.FieldByName(name)
+			var cands []reflect.Value
+			if shrinker := shrinkerFor(f.bindings[name], fv.Type()); shrinker != nil {
+				cands = shrinker.Shrink(fv)
+			} else if len(f.paths) > 0 && (f.paths[name] != nil || hasPathsUnder(f.paths, name)) {
+				cands = f.structuralCandidates(name, fv)
+			} else {
+				continue
+			}
+			for _, cand := range cands {
+				if steps >= maxSteps {
+					return v, steps
+				}
+				steps++
+				if valuesEqual(cand, fv) {
+					// Guard against shrinkers that cycle by returning the
+					// value they were given.
+					continue
+				}
+				candidate := reflect.New(v.Type()).Elem()
+				candidate.Set(v)
+				candidate.FieldByName(name).Set(cand)
+				if applyPredicate(fn, candidate) != nil {
+					v = candidate
+					reduced = true
+					break
+				}
+			}
+		}
+		if !reduced {
+			break
+		}
+	}
+	return v, steps
+}
+
+func applyPredicate(fn func(v reflect.Value) error, v reflect.Value) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if e, ok := r.(error); ok {
+				err = e
+			} else {
+				err = fmt.Errorf("fuzz: check panic: %v", r)
+			}
+		}
+	}()
+	return fn(v)
+}
+
+func valuesEqual(a, b reflect.Value) bool {
+	if !a.IsValid() || !b.IsValid() {
+		return a.IsValid() == b.IsValid()
+	}
+	if !a.Type().Comparable() {
+		return false
+	}
+	return a.Interface() == b.Interface()
+}
+
+// persistFailure writes v's minimized failing value under
+// testdata/fuzz/<testName>, keyed by a hash of its contents, using the same
+// corpus encoding WriteCorpus uses, so a rerun can load it back with Seed.
+func (f *Fuzz) persistFailure(testName string, v reflect.Value) (string, error) {
+	dir := filepath.Join("testdata", "fuzz", testName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	if err := writeCorpusEntry(&buf, v); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	path := filepath.Join(dir, hex.EncodeToString(sum[:]))
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package fuzz
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCheckShrinksFlatField(t *testing.T) {
+	type flat struct {
+		N int
+	}
+	f := Must(New(reflect.TypeOf(flat{})))
+	report, err := f.Check(func(v reflect.Value) error {
+		if v.Interface().(flat).N > 100 {
+			return errIllegal
+		}
+		return nil
+	}, &CheckConfig{Rand: rand.New(rand.NewSource(1)), N: 200})
+	if err != nil {
+		t.Fatalf("Check: %s", err)
+	}
+	if report == nil {
+		t.Fatal("Check: expected a failure report")
+	}
+	if got := report.Value.Interface().(flat).N; got != 101 {
+		t.Fatalf("shrink: got N=%d, want minimal failing N=101", got)
+	}
+}
+
+func TestCheckShrinksNestedPathField(t *testing.T) {
+	type inner struct {
+		N int
+	}
+	type outer struct {
+		Inner inner
+	}
+	f := Must(New(reflect.TypeOf(outer{})))
+	if _, err := f.Option(BindPath("Inner.N", IntGenerator{})); err != nil {
+		t.Fatalf("Option: %s", err)
+	}
+	report, err := f.Check(func(v reflect.Value) error {
+		if v.Interface().(outer).Inner.N > 100 {
+			return errIllegal
+		}
+		return nil
+	}, &CheckConfig{Rand: rand.New(rand.NewSource(1)), N: 200})
+	if err != nil {
+		t.Fatalf("Check: %s", err)
+	}
+	if report == nil {
+		t.Fatal("Check: expected a failure report")
+	}
+	if got := report.Value.Interface().(outer).Inner.N; got != 101 {
+		t.Fatalf("shrink: got nested N=%d, want minimal failing N=101", got)
+	}
+}
+
+// markerStringGen is a Generator that also implements Shrinker, always
+// generating a string longer than 2 bytes and always shrinking to the
+// fixed marker "SHRUNKY" (itself longer than 2 bytes, so it's a stable
+// fixed point). The built-in stringShrinker would instead bisect down to
+// a string of length 2 or less, so whichever one ran is distinguishable
+// from the final value alone.
+type markerStringGen struct{}
+
+func (markerStringGen) Generate(r *rand.Rand, n int) (reflect.Value, error) {
+	length := 3 + r.Intn(5)
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = 'q'
+	}
+	return reflect.ValueOf(string(b)), nil
+}
+
+func (markerStringGen) Shrink(reflect.Value) []reflect.Value {
+	return []reflect.Value{reflect.ValueOf("SHRUNKY")}
+}
+
+func TestCheckShrinksNestedPathFieldWithCustomShrinker(t *testing.T) {
+	type inner struct {
+		S string
+	}
+	type outer struct {
+		Inner inner
+	}
+	f := Must(New(reflect.TypeOf(outer{})))
+	if _, err := f.Option(BindPath("Inner.S", markerStringGen{})); err != nil {
+		t.Fatalf("Option: %s", err)
+	}
+	report, err := f.Check(func(v reflect.Value) error {
+		if len(v.Interface().(outer).Inner.S) > 2 {
+			return errIllegal
+		}
+		return nil
+	}, &CheckConfig{Rand: rand.New(rand.NewSource(1)), N: 10})
+	if err != nil {
+		t.Fatalf("Check: %s", err)
+	}
+	if report == nil {
+		t.Fatal("Check: expected a failure report")
+	}
+	if got := report.Value.Interface().(outer).Inner.S; got != "SHRUNKY" {
+		t.Fatalf("shrink: got Inner.S=%q, want the path-bound Shrinker's marker \"SHRUNKY\" (got the built-in string shrinker instead)", got)
+	}
+}
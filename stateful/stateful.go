@@ -0,0 +1,327 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package stateful builds on top of fuzz's Generator abstraction to drive
+// model-based ("stateful") testing: a sequence of randomly composed
+// commands is executed against both a user-supplied model and the real
+// system under test, asserting that the two stay in sync, in the style of
+// rapid's state-machine testing.
+package stateful
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"caddyshack/fuzz"
+)
+
+var (
+	errNoCommands = errors.New("stateful: machine declares no commands")
+	errBadCommand = errors.New("stateful: command method must take one struct argument and return a Command")
+)
+
+// divergeError reports that a composed sequence's model and real system
+// diverged, per a Command's PostCondition, so Run knows to shrink and
+// persist seq rather than treat err as a generation failure.
+type divergeError struct {
+	cmd string
+	err error
+}
+
+func (e *divergeError) Error() string { return fmt.Sprintf("%s: %s", e.cmd, e.err) }
+func (e *divergeError) Unwrap() error { return e.err }
+
+// State is the user-defined model state a Machine tracks alongside the real
+// system under test.
+type State interface{}
+
+// Command is a single operation a composed sequence may perform.  Check
+// reports whether the command is valid to run against the given model state
+// (its precondition); Apply performs the command against the real system
+// under test and returns the model state after applying it to the model.
+type Command interface {
+	Check(state State) bool
+	Apply(state State) State
+}
+
+// PostConditioner is optionally implemented by a Command to assert
+// additional invariants hold for the state Apply produced.
+type PostConditioner interface {
+	PostCondition(state State) error
+}
+
+// Machine is implemented by the type under test.  Init returns the initial
+// model state.  Every exported method named with the "Command" prefix,
+// taking a single struct argument and returning a Command, is discovered by
+// Run as a candidate command; see Run for the full shape.
+type Machine interface {
+	Init() State
+}
+
+// Resetter is optionally implemented by a Machine to reset the real system
+// under test, mirroring what Init does for the model state.  compose and
+// replay call Reset (when implemented) before executing any sequence
+// against the real system, so that composing a fresh sequence and
+// re-replaying a candidate during shrinking both start from a clean real
+// system rather than carrying over the side effects of whatever Commands
+// ran there before.
+type Resetter interface {
+	Reset()
+}
+
+// resetReal calls machine's Reset, if it implements Resetter.
+func resetReal(machine Machine) {
+	if r, ok := machine.(Resetter); ok {
+		r.Reset()
+	}
+}
+
+// Config controls how Run composes and shrinks command sequences.
+type Config struct {
+	// Rand supplies randomness for command selection and argument
+	// generation.  If nil, a deterministic default source is used.
+	Rand *rand.Rand
+	// Steps is the number of commands composed per sequence.  Defaults to
+	// 20.
+	Steps int
+	// Runs is the number of sequences attempted before Run concludes the
+	// machine holds.  Defaults to 100.
+	Runs int
+	// TestName identifies the testdata/fuzz/<TestName> directory a failing
+	// sequence is persisted under.  Defaults to t.Name().
+	TestName string
+}
+
+// factory describes a discovered command-producing method, together with
+// the Fuzz session used to generate its argument struct.
+type factory struct {
+	name   string
+	method reflect.Value
+	args   *fuzz.Fuzz
+}
+
+// step is one realized command within a composed sequence: the factory it
+// came from and the generated arguments used to produce it.
+type step struct {
+	name string
+	args reflect.Value
+	cmd  Command
+}
+
+// Run discovers machine's commands via reflection, then repeatedly composes
+// and executes random sequences of up to cfg.Steps commands against both
+// machine's model state and machine itself, failing t if the model and the
+// Command's PostCondition (when implemented) ever diverge.  Each composed
+// sequence starts from a clean real system: machine's Reset, if it
+// implements Resetter, runs before every sequence, the same as Init does
+// for the model.  On failure, Run shrinks the offending sequence by
+// removing commands and re-replaying — each replay likewise starting from
+// a freshly Reset real system — reporting the shortest reproducer it
+// finds, and persists it under testdata/fuzz/<TestName> for replay.
+func Run(t *testing.T, machine Machine, cfg Config) {
+	t.Helper()
+
+	r := cfg.Rand
+	if r == nil {
+		r = rand.New(rand.NewSource(1))
+	}
+	steps := cfg.Steps
+	if steps <= 0 {
+		steps = 20
+	}
+	runs := cfg.Runs
+	if runs <= 0 {
+		runs = 100
+	}
+	testName := cfg.TestName
+	if testName == "" {
+		testName = t.Name()
+	}
+
+	factories, err := discoverCommands(machine)
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+
+	for i := 0; i < runs; i++ {
+		seq, err := compose(r, factories, machine, steps)
+		var derr *divergeError
+		switch {
+		case errors.As(err, &derr):
+			min := shrink(machine, seq)
+			if path, perr := persistSequence(testName, min); perr == nil {
+				t.Logf("stateful: minimized failure persisted to %s", path)
+			}
+			t.Fatalf("stateful: model diverged after %d command(s): %s\ncommands: %s", len(min), derr, describe(min))
+			return
+		case err != nil:
+			t.Fatalf("%s", err)
+		}
+	}
+}
+
+// discoverCommands finds every exported method on machine named with the
+// "Command" prefix whose sole parameter is a struct and whose sole result
+// implements Command, building a fuzz.Fuzz session to generate each one's
+// arguments.
+func discoverCommands(machine Machine) ([]factory, error) {
+	cmdType := reflect.TypeOf((*Command)(nil)).Elem()
+	v := reflect.ValueOf(machine)
+	t := v.Type()
+
+	var out []factory
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		if !strings.HasPrefix(m.Name, "Command") {
+			continue
+		}
+		mt := m.Func.Type()
+		if mt.NumIn() != 2 || mt.NumOut() != 1 {
+			return nil, errBadCommand
+		}
+		argTyp := mt.In(1)
+		if argTyp.Kind() != reflect.Struct {
+			return nil, errBadCommand
+		}
+		if !mt.Out(0).Implements(cmdType) {
+			return nil, errBadCommand
+		}
+		args, err := fuzz.New(argTyp)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, factory{name: m.Name, method: v.Method(i), args: args})
+	}
+	if len(out) == 0 {
+		return nil, errNoCommands
+	}
+	return out, nil
+}
+
+// compose builds a sequence of up to n commands, applying each against
+// machine's real system and model exactly once via Apply, skipping
+// factories whose produced command fails its precondition against the
+// running model state.  It stops and returns a *divergeError, together
+// with the sequence executed so far, on the first command whose
+// PostCondition (if implemented) rejects the resulting state; Run relies
+// on Apply only ever being invoked here, not a second time in a separate
+// replay pass.  It calls machine's Reset (if implemented) before touching
+// the real system, so each composed sequence starts from a clean slate.
+func compose(r *rand.Rand, factories []factory, machine Machine, n int) ([]step, error) {
+	resetReal(machine)
+	state := machine.Init()
+	seq := make([]step, 0, n)
+	for i := 0; i < n; i++ {
+		st, ok, err := next(r, factories, state)
+		if err != nil {
+			return seq, err
+		}
+		if !ok {
+			continue
+		}
+		state = st.cmd.Apply(state)
+		seq = append(seq, st)
+		if pc, ok := st.cmd.(PostConditioner); ok {
+			if perr := pc.PostCondition(state); perr != nil {
+				return seq, &divergeError{cmd: st.name, err: perr}
+			}
+		}
+	}
+	return seq, nil
+}
+
+// next draws one factory at random and generates a command from it,
+// returning ok=false if the command's precondition rejects the state.
+func next(r *rand.Rand, factories []factory, state State) (step, bool, error) {
+	f := factories[r.Intn(len(factories))]
+	argv, err := f.args.Value(r, 0)
+	if err != nil {
+		return step{}, false, err
+	}
+	out := f.method.Call([]reflect.Value{argv})
+	cmd, ok := out[0].Interface().(Command)
+	if !ok || !cmd.Check(state) {
+		return step{}, false, nil
+	}
+	return step{name: f.name, args: argv, cmd: cmd}, true, nil
+}
+
+// replay executes seq against machine's real system and model from a clean
+// slate — calling machine's Reset (if implemented) before Init, the same
+// as compose — failing on the first command whose PostCondition (if
+// implemented) rejects the resulting state.
+func replay(machine Machine, seq []step) error {
+	resetReal(machine)
+	state := machine.Init()
+	for _, s := range seq {
+		state = s.cmd.Apply(state)
+		if pc, ok := s.cmd.(PostConditioner); ok {
+			if err := pc.PostCondition(state); err != nil {
+				return fmt.Errorf("%s: %w", s.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// shrink removes commands from seq one at a time, keeping any removal that
+// still reproduces a divergence, until no further command can be dropped.
+func shrink(machine Machine, seq []step) []step {
+	for {
+		reduced := false
+		for i := range seq {
+			candidate := make([]step, 0, len(seq)-1)
+			candidate = append(candidate, seq[:i]...)
+			candidate = append(candidate, seq[i+1:]...)
+			if replay(machine, candidate) != nil {
+				seq = candidate
+				reduced = true
+				break
+			}
+		}
+		if !reduced {
+			return seq
+		}
+	}
+}
+
+func describe(seq []step) string {
+	names := make([]string, len(seq))
+	for i, s := range seq {
+		names[i] = fmt.Sprintf("%s(%#v)", s.name, s.args.Interface())
+	}
+	return strings.Join(names, " -> ")
+}
+
+// persistSequence writes the minimized failing command trace under
+// testdata/fuzz/<testName>, keyed by test name, so a later run can inspect
+// or replay it.
+func persistSequence(testName string, seq []step) (string, error) {
+	dir := filepath.Join("testdata", "fuzz", testName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "sequence")
+	data := []byte("// minimized by stateful.Run\n" + describe(seq) + "\n")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2014 Matt T. Proud
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stateful
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// counter is the real system under test: Apply increments it once per
+// command, mirroring a resource Init cannot cheaply roll back.
+type counter struct {
+	real *int
+}
+
+type incrArgs struct{}
+
+type incrCommand struct {
+	real *int
+}
+
+func (c incrCommand) Check(State) bool { return true }
+
+func (c incrCommand) Apply(state State) State {
+	*c.real++
+	return state.(int) + 1
+}
+
+func (m counter) Init() State { return 0 }
+
+func (m counter) CommandIncr(incrArgs) Command {
+	return incrCommand{real: m.real}
+}
+
+func TestRunAppliesEachCommandExactlyOnce(t *testing.T) {
+	real := 0
+	m := counter{real: &real}
+	Run(t, m, Config{Rand: rand.New(rand.NewSource(1)), Steps: 4, Runs: 1})
+	if real != 4 {
+		t.Fatalf("real system got %d Apply calls, want 4 (one per composed command)", real)
+	}
+}
+
+// resettableCounter is a counter whose real resource Reset actually rolls
+// back, the way a Resetter-implementing Machine is expected to behave.
+type resettableCounter struct {
+	real *int
+}
+
+type boundedIncrCommand struct {
+	real *int
+}
+
+func (c boundedIncrCommand) Check(State) bool { return true }
+
+func (c boundedIncrCommand) Apply(state State) State {
+	*c.real++
+	return state.(int) + 1
+}
+
+func (c boundedIncrCommand) PostCondition(State) error {
+	if *c.real > 2 {
+		return fmt.Errorf("real exceeded 2: got %d", *c.real)
+	}
+	return nil
+}
+
+func (m resettableCounter) Init() State { return 0 }
+
+func (m resettableCounter) Reset() { *m.real = 0 }
+
+func (m resettableCounter) CommandIncr(incrArgs) Command {
+	return boundedIncrCommand{real: m.real}
+}
+
+// TestShrinkReplaysFromAFreshRealSystem reproduces the maintainer's report:
+// without resetting the real system between replay attempts, shrinking a
+// 3-command failing sequence (found once *real > 2) kept accumulating
+// Apply side effects across replay calls and reported a false single-command
+// reproducer. With Reset wired in, the minimal sequence shrink finds must
+// actually still diverge when replayed against a truly fresh real system.
+func TestShrinkReplaysFromAFreshRealSystem(t *testing.T) {
+	real := 0
+	m := resettableCounter{real: &real}
+
+	factories, err := discoverCommands(m)
+	if err != nil {
+		t.Fatalf("discoverCommands: %s", err)
+	}
+	seq, err := compose(rand.New(rand.NewSource(1)), factories, m, 3)
+	var derr *divergeError
+	if !errors.As(err, &derr) {
+		t.Fatalf("compose: got err=%v, want a *divergeError after 3 commands", err)
+	}
+	if len(seq) != 3 {
+		t.Fatalf("compose: got a %d-command sequence, want 3", len(seq))
+	}
+
+	min := shrink(m, seq)
+	if len(min) != 3 {
+		t.Fatalf("shrink: got a %d-command minimal sequence, want 3 (2 commands alone can't push real past 2)", len(min))
+	}
+
+	// Replay the reported minimal sequence against a truly fresh real
+	// system, independent of whatever state compose/shrink left behind,
+	// and confirm it actually reproduces the divergence.
+	fresh := 0
+	fm := resettableCounter{real: &fresh}
+	if err := replay(fm, min); err == nil {
+		t.Fatal("replay: minimal sequence did not reproduce the divergence against a fresh real system")
+	}
+}